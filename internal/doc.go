@@ -0,0 +1,10 @@
+// Package internal implements the muxer backends this tool merges demuxed
+// m4s video/audio into a finished file with: the embedded MP4Box binary
+// (mp4boxMuxer), a pure-Go MP4 rewrite (nativeMuxer), FFmpeg (ffmpegMuxer),
+// and MPEG-TS/HLS segmentation (MuxHLS).
+//
+// None of it is wired to a CLI yet — this tree has no main/cmd package, so
+// --mp4box-path, --muxer, --format=hls and --segment-duration are all just
+// struct fields and vars (MP4BoxPathOverride, ResolveMuxer's input, HLSOptions)
+// waiting for an entry point to parse flags and set them.
+package internal
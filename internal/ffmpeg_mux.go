@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/sirupsen/logrus"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// FFmpegOptions configures the FFmpeg backend's output beyond a lossless
+// remux, letting callers pick a container/codec or extract audio only.
+// See the package doc for how this gets constructed until a CLI exists.
+type FFmpegOptions struct {
+	// VideoCodec/AudioCodec default to "copy" (lossless remux) when empty.
+	VideoCodec string
+	AudioCodec string
+	// AudioOnly drops the video stream, e.g. to produce an MP3/FLAC output.
+	AudioOnly bool
+}
+
+// ffmpegMuxer implements Muxer by piping the demuxed segments into FFmpeg,
+// which supports output formats and re-encoding MP4Box cannot.
+type ffmpegMuxer struct {
+	Options FFmpegOptions
+}
+
+func (m ffmpegMuxer) Mux(videoPath, audioPath, outputPath string) error {
+	return MuxFFmpeg(videoPath, audioPath, outputPath, m.Options)
+}
+
+// MuxFFmpeg concatenates the video and audio m4s segments via FFmpeg,
+// defaulting to "-c copy" for a lossless remux. Set opts.AudioCodec /
+// opts.VideoCodec to re-encode, or opts.AudioOnly to extract the audio track.
+func MuxFFmpeg(videoPath, audioPath, outputPath string, opts FFmpegOptions) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		logrus.Error("未找到ffmpeg,请先安装ffmpeg或改用mp4box/native混流后端")
+		return err
+	}
+
+	videoCodec := opts.VideoCodec
+	if videoCodec == "" {
+		videoCodec = "copy"
+	}
+	audioCodec := opts.AudioCodec
+	if audioCodec == "" {
+		audioCodec = "copy"
+	}
+
+	kwArgs := ffmpeg.KwArgs{"c:a": audioCodec}
+	if !opts.AudioOnly {
+		kwArgs["c:v"] = videoCodec
+	} else {
+		kwArgs["vn"] = ""
+	}
+
+	streams := []*ffmpeg.Stream{ffmpeg.Input(audioPath)}
+	if !opts.AudioOnly {
+		streams = append([]*ffmpeg.Stream{ffmpeg.Input(videoPath)}, streams...)
+	}
+
+	logrus.Info("使用FFmpeg混流/转码")
+	err := ffmpeg.Output(streams, outputPath, kwArgs).OverWriteOutput().ErrorToStdOut().Run()
+	if err != nil {
+		logrus.Error(fmt.Errorf("ffmpeg混流失败: %w", err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yapingcat/gomedia/go-codec"
+	"github.com/yapingcat/gomedia/go-mpeg2"
+)
+
+// mpegTSClock is the 90kHz clock MPEG-TS PTS/DTS values are counted in.
+const mpegTSClock = 90000
+
+// videoFrameRate is the assumed source frame rate used to space PTS values
+// across the demuxed H.264 elementary stream; bilibili's own segments are
+// near-universally 25fps.
+const videoFrameRate = 25
+
+// HLSOptions configures the TS/m3u8 segmenter.
+type HLSOptions struct {
+	// SegmentDuration is the target length of each .ts segment, e.g. 6s.
+	SegmentDuration time.Duration
+}
+
+// MuxHLS re-muxes a video and an audio elementary stream into MPEG-TS
+// segments plus an .m3u8 playlist under outDir, so the converted archive
+// can be served to any HLS-capable player without a second transcoding
+// pass. videoPath is read as a raw Annex-B H.264 stream and audioPath as a
+// raw ADTS AAC stream; MP4 (m4s) box structure in either file is not
+// parsed.
+func MuxHLS(videoPath, audioPath, outDir string, opts HLSOptions) error {
+	if opts.SegmentDuration <= 0 {
+		opts.SegmentDuration = 6 * time.Second
+	}
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	videoData, err := os.ReadFile(videoPath)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	audioData, err := os.ReadFile(audioPath)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	videoFrames := splitH264Frames(videoData)
+	audioFrames, err := splitAACFrames(audioData)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	segmenter := newTSSegmenter(outDir, opts.SegmentDuration)
+	muxer := mpeg2.NewTSMuxer()
+	muxer.OnPacket = segmenter.onPacket
+
+	pesVideoID := muxer.AddStream(mpeg2.TS_STREAM_H264)
+	pesAudioID := muxer.AddStream(mpeg2.TS_STREAM_AAC)
+
+	// Writing every video frame before any audio frame (as an earlier
+	// version of this function did) means segment rollover - which only
+	// ever observes the video clock - finishes before audio exists, so
+	// the whole audio track piles up in whichever segment is still open.
+	// Interleaving in PTS order keeps each .ts segment self-contained.
+	for _, frame := range interleaveFrames(videoFrames, audioFrames) {
+		switch frame.kind {
+		case streamVideo:
+			segmenter.observeVideoPTS(frame.pts)
+			muxer.Write(pesVideoID, frame.data, frame.pts, frame.pts)
+		case streamAudio:
+			muxer.Write(pesAudioID, frame.data, frame.pts, frame.pts)
+		}
+	}
+
+	if err := segmenter.flush(); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	logrus.Infof("HLS切片完成,共%d个分片,播放列表: %s", segmenter.sequence, segmenter.playlistPath())
+	return segmenter.writePlaylist()
+}
+
+// tsFrame is one access unit (NALU or AAC frame) tagged with the 90kHz PTS
+// it should be muxed at.
+type tsFrame struct {
+	pts  uint64
+	data []byte
+}
+
+// splitH264Frames splits the demuxed H.264 elementary stream into NALUs,
+// spacing PTS values 90kHz-clock-ticks apart per videoFrameRate.
+func splitH264Frames(data []byte) []tsFrame {
+	const ticksPerFrame = mpegTSClock / videoFrameRate
+	var frames []tsFrame
+	pts := uint64(0)
+	codec.SplitFrameWithStartCode(data, func(nalu []byte) bool {
+		frames = append(frames, tsFrame{pts: pts, data: nalu})
+		pts += ticksPerFrame
+		return true
+	})
+	return frames
+}
+
+// splitAACFrames walks the demuxed AAC elementary stream frame-by-frame
+// using its ADTS headers, spacing PTS values by each frame's real duration
+// (1024 samples / that frame's sample rate).
+func splitAACFrames(data []byte) ([]tsFrame, error) {
+	var frames []tsFrame
+	pts := uint64(0)
+	pos := 0
+	for pos < len(data) {
+		frame, sampleRate, ok := parseADTSFrame(data[pos:])
+		if !ok {
+			return nil, fmt.Errorf("invalid ADTS frame at offset %d", pos)
+		}
+		frames = append(frames, tsFrame{pts: pts, data: frame})
+		pts += mpegTSClock * adtsSamplesPerFrame / uint64(sampleRate)
+		pos += len(frame)
+	}
+	return frames, nil
+}
+
+// streamKind tags a muxFrame with which elementary stream it came from.
+type streamKind int
+
+const (
+	streamVideo streamKind = iota
+	streamAudio
+)
+
+// muxFrame is one tsFrame ready to be written to the TS muxer, tagged with
+// its originating stream.
+type muxFrame struct {
+	kind streamKind
+	pts  uint64
+	data []byte
+}
+
+// interleaveFrames merges the video and audio frame lists into a single
+// ascending-PTS sequence, so muxing them in order keeps every rolled-over
+// segment self-contained instead of writing one whole stream before the
+// other.
+func interleaveFrames(video, audio []tsFrame) []muxFrame {
+	merged := make([]muxFrame, 0, len(video)+len(audio))
+	vi, ai := 0, 0
+	for vi < len(video) || ai < len(audio) {
+		if ai >= len(audio) || (vi < len(video) && video[vi].pts <= audio[ai].pts) {
+			merged = append(merged, muxFrame{kind: streamVideo, pts: video[vi].pts, data: video[vi].data})
+			vi++
+		} else {
+			merged = append(merged, muxFrame{kind: streamAudio, pts: audio[ai].pts, data: audio[ai].data})
+			ai++
+		}
+	}
+	return merged
+}
+
+// tsSegment records one already-written .ts file and the real duration of
+// video it ended up holding, for the m3u8's #EXTINF entries.
+type tsSegment struct {
+	name     string
+	duration time.Duration
+}
+
+// tsSegmenter rolls the TS muxer's output into fixed-duration .ts files,
+// rollover being driven by the video PTS stream observed via
+// observeVideoPTS, and tracks the matching #EXTINF entries for the final
+// m3u8 playlist.
+type tsSegmenter struct {
+	outDir       string
+	targetTicks  uint64 // opts.SegmentDuration in 90kHz ticks
+	sequence     int
+	entries      []tsSegment
+	current      *os.File
+	segmentStart uint64
+	lastPTS      uint64
+	havePTS      bool
+}
+
+func newTSSegmenter(outDir string, duration time.Duration) *tsSegmenter {
+	return &tsSegmenter{
+		outDir:      outDir,
+		targetTicks: uint64(duration.Milliseconds()) * mpegTSClock / 1000,
+	}
+}
+
+func (s *tsSegmenter) segmentName(n int) string {
+	return fmt.Sprintf("segment%d.ts", n)
+}
+
+func (s *tsSegmenter) playlistPath() string {
+	return filepath.Join(s.outDir, "playlist.m3u8")
+}
+
+// onPacket is the mpeg2.TSMuxer callback; it appends muxed TS packets to
+// whichever segment file is currently open.
+func (s *tsSegmenter) onPacket(pkg []byte) {
+	if s.current == nil {
+		s.rollSegment(0)
+	}
+	if _, err := s.current.Write(pkg); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// observeVideoPTS is called once per video frame before it's muxed. It
+// opens the first segment on the first call, and rolls to a new segment
+// once targetTicks worth of video PTS has elapsed in the current one.
+func (s *tsSegmenter) observeVideoPTS(pts uint64) {
+	if s.current == nil {
+		s.rollSegment(0)
+		s.segmentStart = pts
+		s.havePTS = true
+		s.lastPTS = pts
+		return
+	}
+	if !s.havePTS {
+		s.segmentStart = pts
+		s.havePTS = true
+		s.lastPTS = pts
+		return
+	}
+	if pts-s.segmentStart >= s.targetTicks {
+		s.rollSegment(time.Duration(pts-s.segmentStart) * time.Second / mpegTSClock)
+		s.segmentStart = pts
+	}
+	s.lastPTS = pts
+}
+
+// rollSegment closes the current segment file (if any), recording
+// finishedDuration as its real length, and opens the next one.
+func (s *tsSegmenter) rollSegment(finishedDuration time.Duration) {
+	if s.current != nil {
+		s.current.Close()
+		s.entries = append(s.entries, tsSegment{name: s.segmentName(s.sequence), duration: finishedDuration})
+		s.sequence++
+	}
+	f, err := os.Create(filepath.Join(s.outDir, s.segmentName(s.sequence)))
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+	s.current = f
+}
+
+// flush closes out the final, possibly short, segment, recording its real
+// elapsed video duration rather than the configured target.
+func (s *tsSegmenter) flush() error {
+	if s.current == nil {
+		return nil
+	}
+	if err := s.current.Close(); err != nil {
+		return err
+	}
+	finishedDuration := time.Duration(0)
+	if s.havePTS {
+		finishedDuration = time.Duration(s.lastPTS-s.segmentStart) * time.Second / mpegTSClock
+	}
+	s.entries = append(s.entries, tsSegment{name: s.segmentName(s.sequence), duration: finishedDuration})
+	s.sequence++
+	s.current = nil
+	return nil
+}
+
+func (s *tsSegmenter) writePlaylist() error {
+	f, err := os.Create(s.playlistPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	targetDuration := time.Duration(s.targetTicks) * time.Second / mpegTSClock
+
+	fmt.Fprintln(f, "#EXTM3U")
+	fmt.Fprintln(f, "#EXT-X-VERSION:3")
+	fmt.Fprintf(f, "#EXT-X-TARGETDURATION:%d\n", int(targetDuration.Seconds()+0.999))
+	fmt.Fprintln(f, "#EXT-X-MEDIA-SEQUENCE:0")
+	for _, seg := range s.entries {
+		fmt.Fprintf(f, "#EXTINF:%.3f,\n%s\n", seg.duration.Seconds(), seg.name)
+	}
+	fmt.Fprintln(f, "#EXT-X-ENDLIST")
+	return nil
+}
+
+// adtsSamplesPerFrame is the fixed number of PCM samples an AAC-LC frame
+// (the profile bilibili segments use) encodes.
+const adtsSamplesPerFrame = 1024
+
+// adtsSampleRates maps an ADTS sampling_frequency_index to its Hz value.
+var adtsSampleRates = [...]int{
+	96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050,
+	16000, 12000, 11025, 8000, 7350,
+}
+
+// parseADTSFrame reads a single ADTS frame (header + payload) starting at
+// data[0], returning the frame's bytes and sample rate. It supports both
+// the 7-byte (no CRC) and 9-byte (CRC present) ADTS header forms.
+func parseADTSFrame(data []byte) (frame []byte, sampleRate int, ok bool) {
+	if len(data) < 7 || data[0] != 0xFF || data[1]&0xF0 != 0xF0 {
+		return nil, 0, false
+	}
+	protectionAbsent := data[1]&0x01 == 1
+	headerLen := 9
+	if protectionAbsent {
+		headerLen = 7
+	}
+	if len(data) < headerLen {
+		return nil, 0, false
+	}
+
+	samplingFreqIndex := (data[2] >> 2) & 0x0F
+	if int(samplingFreqIndex) >= len(adtsSampleRates) {
+		return nil, 0, false
+	}
+	// The 13-bit frame_length field spans parts of three bytes.
+	frameLength := (int(data[3]&0x03) << 11) | (int(data[4]) << 3) | (int(data[5]) >> 5)
+	if frameLength < headerLen || frameLength > len(data) {
+		return nil, 0, false
+	}
+
+	return data[:frameLength], adtsSampleRates[samplingFreqIndex], true
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTSSegmenterRollsOverOnDuration(t *testing.T) {
+	dir := t.TempDir()
+	segmenter := newTSSegmenter(dir, 2*time.Second) // 180000 ticks at 90kHz
+
+	const ticksPerFrame = mpegTSClock / videoFrameRate // 3600, 25fps
+	pts := uint64(0)
+	for i := 0; i < 130; i++ { // ~130*40ms = 5.2s of video
+		segmenter.observeVideoPTS(pts)
+		pts += ticksPerFrame
+	}
+	if err := segmenter.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	// 5.2s of video at a 2s target should produce 3 segments (2s, 2s, ~1.2s).
+	if got, want := segmenter.sequence, 3; got != want {
+		t.Fatalf("sequence = %d, want %d", got, want)
+	}
+	if got, want := len(segmenter.entries), 3; got != want {
+		t.Fatalf("len(entries) = %d, want %d", got, want)
+	}
+	for i, seg := range segmenter.entries {
+		if seg.name != segmenter.segmentName(i) {
+			t.Errorf("entries[%d].name = %q, want %q", i, seg.name, segmenter.segmentName(i))
+		}
+		if _, err := os.Stat(filepath.Join(dir, seg.name)); err != nil {
+			t.Errorf("segment file %s missing: %v", seg.name, err)
+		}
+	}
+	if segmenter.entries[0].duration < 2*time.Second-time.Millisecond {
+		t.Errorf("entries[0].duration = %v, want ~2s", segmenter.entries[0].duration)
+	}
+}
+
+func TestTSSegmenterFlushUsesElapsedPTSNotTarget(t *testing.T) {
+	dir := t.TempDir()
+	segmenter := newTSSegmenter(dir, 2*time.Second) // 180000 ticks at 90kHz
+
+	const ticksPerFrame = mpegTSClock / videoFrameRate // 3600, 25fps
+	pts := uint64(0)
+	for i := 0; i < 25; i++ { // 25 frames at 25fps = exactly 1s, well under the 2s target
+		segmenter.observeVideoPTS(pts)
+		pts += ticksPerFrame
+	}
+	if err := segmenter.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	if got, want := segmenter.sequence, 1; got != want {
+		t.Fatalf("sequence = %d, want %d", got, want)
+	}
+	got := segmenter.entries[0].duration
+	if got >= 2*time.Second {
+		t.Errorf("entries[0].duration = %v, want ~1s (the real elapsed PTS), not the full 2s target", got)
+	}
+	if got < 900*time.Millisecond || got > 1100*time.Millisecond {
+		t.Errorf("entries[0].duration = %v, want ~1s", got)
+	}
+}
+
+func TestInterleaveFramesOrdersByPTS(t *testing.T) {
+	video := []tsFrame{{pts: 0, data: []byte("v0")}, {pts: 3600, data: []byte("v1")}, {pts: 7200, data: []byte("v2")}}
+	audio := []tsFrame{{pts: 0, data: []byte("a0")}, {pts: 2048, data: []byte("a1")}, {pts: 4096, data: []byte("a2")}}
+
+	merged := interleaveFrames(video, audio)
+
+	wantOrder := []struct {
+		kind streamKind
+		pts  uint64
+	}{
+		{streamVideo, 0}, {streamAudio, 0}, {streamAudio, 2048},
+		{streamVideo, 3600}, {streamAudio, 4096}, {streamVideo, 7200},
+	}
+	if len(merged) != len(wantOrder) {
+		t.Fatalf("len(merged) = %d, want %d", len(merged), len(wantOrder))
+	}
+	for i, want := range wantOrder {
+		if merged[i].kind != want.kind || merged[i].pts != want.pts {
+			t.Errorf("merged[%d] = {kind:%v pts:%d}, want {kind:%v pts:%d}", i, merged[i].kind, merged[i].pts, want.kind, want.pts)
+		}
+	}
+
+	// No audio frame may be dropped by the merge.
+	seenAudioCount := 0
+	for _, f := range merged {
+		if f.kind == streamAudio {
+			seenAudioCount++
+		}
+	}
+	if seenAudioCount != len(audio) {
+		t.Fatalf("interleaveFrames dropped audio frames: got %d, want %d", seenAudioCount, len(audio))
+	}
+}
+
+func TestTSSegmenterNoRolloverWithinTarget(t *testing.T) {
+	dir := t.TempDir()
+	segmenter := newTSSegmenter(dir, 6*time.Second)
+
+	segmenter.observeVideoPTS(0)
+	segmenter.observeVideoPTS(mpegTSClock / videoFrameRate)
+	if err := segmenter.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	if got, want := segmenter.sequence, 1; got != want {
+		t.Fatalf("sequence = %d, want %d (no rollover expected within one target window)", got, want)
+	}
+}
+
+func TestParseADTSFrame(t *testing.T) {
+	payload := []byte("AAC-PAYLOAD-BYTES")
+	frame := buildADTSFrame(t, 4, payload) // sampling_freq_index 4 = 44100Hz
+
+	got, sampleRate, ok := parseADTSFrame(frame)
+	if !ok {
+		t.Fatal("parseADTSFrame: !ok")
+	}
+	if sampleRate != 44100 {
+		t.Errorf("sampleRate = %d, want 44100", sampleRate)
+	}
+	if string(got) != string(frame) {
+		t.Errorf("frame length = %d, want %d", len(got), len(frame))
+	}
+}
+
+func TestParseADTSFrameRejectsGarbage(t *testing.T) {
+	if _, _, ok := parseADTSFrame([]byte{0x00, 0x01, 0x02}); ok {
+		t.Fatal("expected ok=false for non-ADTS data")
+	}
+}
+
+// buildADTSFrame assembles a minimal 7-byte ADTS header (no CRC) followed
+// by payload, with the given sampling_frequency_index.
+func buildADTSFrame(t *testing.T, samplingFreqIndex byte, payload []byte) []byte {
+	t.Helper()
+	frameLength := 7 + len(payload)
+	header := make([]byte, 7)
+	header[0] = 0xFF
+	header[1] = 0xF1 // sync (cont) + MPEG-4 + layer 00 + protection_absent=1
+	header[2] = (1 << 6) | (samplingFreqIndex << 2)
+	header[3] = byte((frameLength >> 11) & 0x03)
+	header[4] = byte((frameLength >> 3) & 0xFF)
+	header[5] = byte((frameLength&0x07)<<5) | 0x1F
+	header[6] = 0xFC
+	return append(header, payload...)
+}
@@ -4,23 +4,18 @@ package internal
 
 import (
 	_ "embed"
-	utils "github.com/mzky/utils/common"
-	"github.com/sirupsen/logrus"
-	"os"
-	"path/filepath"
 )
 
 //go:embed linux/MP4Box
 var mp4Box []byte
 
+// mp4BoxSHA256 and mp4BoxVersion pin the embedded MP4Box release so stale
+// or tampered copies left over from a previous version get re-extracted.
+const (
+	mp4BoxSHA256  = "cd328f57162d2d17b2432e7bc50aaf14408429adbc6b970b615fb02fc13b58c7"
+	mp4BoxVersion = "GPAC version 2.4-DEV"
+)
+
 func GetMP4Box() string {
-	mp4boxPath := filepath.Join(os.TempDir(), "MP4Box") // 指定ffmpeg路径
-	if !utils.IsExist(mp4boxPath) {
-		logrus.Info("第一次运行,自动释放MP4Box")
-		if err := os.WriteFile(mp4boxPath, mp4Box, os.ModePerm); err != nil {
-			logrus.Error(err)
-			logrus.Fatal("释放MP4Box失败,查看文件权限是否正常")
-		}
-	}
-	return mp4boxPath
+	return resolveMP4Box(mp4Box, mp4BoxSHA256, mp4BoxVersion, "MP4Box")
 }
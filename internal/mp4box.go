@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	utils "github.com/mzky/utils/common"
+	"github.com/sirupsen/logrus"
+)
+
+// MP4BoxPathOverride lets power users point GetMP4Box at a system-installed
+// GPAC instead of the embedded binary. See the package doc for how this
+// gets set until a CLI exists.
+var MP4BoxPathOverride string
+
+// resolveMP4Box extracts the embedded MP4Box binary to destName under
+// os.TempDir(), re-extracting it whenever the on-disk copy's SHA-256 no
+// longer matches wantSHA256 (stale release, tampered/corrupt file, etc).
+// It logs the resolved MP4Box version on the way out.
+func resolveMP4Box(embedded []byte, wantSHA256, version, destName string) string {
+	if MP4BoxPathOverride != "" {
+		logrus.Infof("使用用户指定的MP4Box: %s", MP4BoxPathOverride)
+		logMP4BoxVersion(MP4BoxPathOverride)
+		return MP4BoxPathOverride
+	}
+
+	mp4boxPath := tempPath(destName)
+	if utils.IsExist(mp4boxPath) && sha256Hex(mp4boxPath) == wantSHA256 {
+		logMP4BoxVersion(mp4boxPath)
+		return mp4boxPath
+	}
+
+	logrus.Infof("首次运行或已存在版本校验不通过,重新释放MP4Box(版本 %s)", version)
+	if err := os.WriteFile(mp4boxPath, embedded, os.ModePerm); err != nil {
+		logrus.Error(err)
+		logrus.Fatal("释放MP4Box失败,查看文件权限是否正常")
+	}
+	logMP4BoxVersion(mp4boxPath)
+	return mp4boxPath
+}
+
+// tempPath joins destName onto os.TempDir(), matching where previous
+// releases already unpacked MP4Box.
+func tempPath(destName string) string {
+	return filepath.Join(os.TempDir(), destName)
+}
+
+// sha256Hex returns the lowercase hex SHA-256 digest of the file at path,
+// or "" if it can't be read.
+func sha256Hex(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// logMP4BoxVersion invokes `MP4Box -version` and logs the result so users
+// can confirm which binary actually ran.
+func logMP4BoxVersion(path string) {
+	out, err := exec.Command(path, "-version").CombinedOutput()
+	if err != nil {
+		logrus.Warnf("获取MP4Box版本失败: %v", err)
+		return
+	}
+	logrus.Infof("MP4Box版本: %s", strings.TrimSpace(string(out)))
+}
@@ -0,0 +1,19 @@
+//go:build darwin && amd64
+
+package internal
+
+import (
+	_ "embed"
+)
+
+//go:embed darwin/MP4Box
+var mp4Box []byte
+
+const (
+	mp4BoxSHA256  = "23c7ab6fa0b94d5812808c1b433408fd200e211bb8dd00b8f3fa8747c040c818"
+	mp4BoxVersion = "GPAC version 2.4-DEV"
+)
+
+func GetMP4Box() string {
+	return resolveMP4Box(mp4Box, mp4BoxSHA256, mp4BoxVersion, "MP4Box")
+}
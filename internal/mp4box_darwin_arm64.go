@@ -0,0 +1,19 @@
+//go:build darwin && arm64
+
+package internal
+
+import (
+	_ "embed"
+)
+
+//go:embed darwin/MP4Box-arm64
+var mp4Box []byte
+
+const (
+	mp4BoxSHA256  = "7b6ddf6779e67bc39e349488fe6f65ccf608cdd11f896dc88d27aa1690998b1c"
+	mp4BoxVersion = "GPAC version 2.4-DEV"
+)
+
+func GetMP4Box() string {
+	return resolveMP4Box(mp4Box, mp4BoxSHA256, mp4BoxVersion, "MP4Box")
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sha256HexBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSha256Hex(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// echo -n hello | sha256sum
+	const want = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if got := sha256Hex(path); got != want {
+		t.Errorf("sha256Hex = %s, want %s", got, want)
+	}
+}
+
+func TestSha256HexMissingFile(t *testing.T) {
+	if got := sha256Hex(filepath.Join(t.TempDir(), "missing")); got != "" {
+		t.Errorf("sha256Hex(missing file) = %q, want empty string", got)
+	}
+}
+
+func TestResolveMP4BoxReExtractsOnStaleBinary(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	embedded := []byte("real-mp4box-binary")
+	wantSHA := sha256HexBytes(embedded)
+
+	destPath := tempPath("MP4Box")
+	if err := os.WriteFile(destPath, []byte("stale-or-tampered-binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := resolveMP4Box(embedded, wantSHA, "v1", "MP4Box")
+	if got != destPath {
+		t.Fatalf("resolveMP4Box returned %q, want %q", got, destPath)
+	}
+	data, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(embedded) {
+		t.Errorf("stale binary was not re-extracted: got %q, want %q", data, embedded)
+	}
+}
+
+func TestResolveMP4BoxReusesMatchingBinary(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	embedded := []byte("real-mp4box-binary")
+	wantSHA := sha256HexBytes(embedded)
+
+	destPath := tempPath("MP4Box")
+	if err := os.WriteFile(destPath, embedded, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTimeBefore := info.ModTime()
+
+	got := resolveMP4Box(embedded, wantSHA, "v1", "MP4Box")
+	if got != destPath {
+		t.Fatalf("resolveMP4Box returned %q, want %q", got, destPath)
+	}
+	info, err = os.Stat(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modTimeBefore) {
+		t.Error("resolveMP4Box rewrote a binary whose SHA-256 already matched")
+	}
+}
+
+func TestResolveMP4BoxHonorsOverride(t *testing.T) {
+	MP4BoxPathOverride = "/usr/local/bin/MP4Box"
+	t.Cleanup(func() { MP4BoxPathOverride = "" })
+
+	got := resolveMP4Box([]byte("embedded"), "unused", "v1", "MP4Box")
+	if got != MP4BoxPathOverride {
+		t.Errorf("resolveMP4Box = %q, want override %q", got, MP4BoxPathOverride)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package internal
+
+import (
+	_ "embed"
+)
+
+//go:embed windows/MP4Box.exe
+var mp4Box []byte
+
+const (
+	mp4BoxSHA256  = "27c5db3461ad659064a73400edaae9962051aeedd2e980e0a34e4d659a289a38"
+	mp4BoxVersion = "GPAC version 2.4-DEV"
+)
+
+func GetMP4Box() string {
+	return resolveMP4Box(mp4Box, mp4BoxSHA256, mp4BoxVersion, "MP4Box.exe")
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"os/exec"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Muxer combines a demuxed video and audio m4s elementary stream into a
+// single output file, regardless of which tool does the work underneath.
+type Muxer interface {
+	Mux(videoPath, audioPath, outputPath string) error
+}
+
+// MuxerBackend selects which Muxer implementation callers get from NewMuxer.
+type MuxerBackend string
+
+const (
+	// MuxerMP4Box shells out to the embedded/external MP4Box binary.
+	MuxerMP4Box MuxerBackend = "mp4box"
+	// MuxerNative rewrites the MP4 container in pure Go, no external binary required.
+	MuxerNative MuxerBackend = "native"
+	// MuxerFFmpeg pipes the segments through FFmpeg, enabling re-encoding
+	// and output formats MP4Box can't produce (MP3/FLV/MKV, audio-only...).
+	MuxerFFmpeg MuxerBackend = "ffmpeg"
+)
+
+// ResolveMuxer parses a --muxer flag value, defaulting to MP4Box for
+// backwards compatibility with existing installs. See the package doc for
+// how this gets called until a CLI exists.
+func ResolveMuxer(name string) MuxerBackend {
+	switch MuxerBackend(name) {
+	case MuxerNative:
+		return MuxerNative
+	case MuxerFFmpeg:
+		return MuxerFFmpeg
+	default:
+		return MuxerMP4Box
+	}
+}
+
+// NewMuxer returns the Muxer implementation for the given backend.
+func NewMuxer(backend MuxerBackend) Muxer {
+	switch backend {
+	case MuxerNative:
+		return nativeMuxer{}
+	case MuxerFFmpeg:
+		return ffmpegMuxer{}
+	default:
+		return mp4boxMuxer{}
+	}
+}
+
+// mp4boxMuxer implements Muxer by shelling out to the resolved MP4Box binary.
+type mp4boxMuxer struct{}
+
+func (mp4boxMuxer) Mux(videoPath, audioPath, outputPath string) error {
+	mp4boxPath := GetMP4Box()
+	cmd := exec.Command(mp4boxPath, "-add", videoPath, "-add", audioPath, "-new", outputPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		logrus.Errorf("MP4Box混流失败: %v, 输出: %s", err, out)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package internal
+
+import "testing"
+
+func TestResolveMuxer(t *testing.T) {
+	cases := []struct {
+		name string
+		want MuxerBackend
+	}{
+		{"native", MuxerNative},
+		{"ffmpeg", MuxerFFmpeg},
+		{"mp4box", MuxerMP4Box},
+		{"", MuxerMP4Box},
+		{"bogus", MuxerMP4Box},
+	}
+	for _, c := range cases {
+		if got := ResolveMuxer(c.name); got != c.want {
+			t.Errorf("ResolveMuxer(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNewMuxerReturnsMatchingBackend(t *testing.T) {
+	cases := []struct {
+		backend MuxerBackend
+		want    Muxer
+	}{
+		{MuxerNative, nativeMuxer{}},
+		{MuxerFFmpeg, ffmpegMuxer{}},
+		{MuxerMP4Box, mp4boxMuxer{}},
+	}
+	for _, c := range cases {
+		got := NewMuxer(c.backend)
+		if got != c.want {
+			t.Errorf("NewMuxer(%q) = %#v, want %#v", c.backend, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// nativeMuxer implements Muxer by rewriting the MP4 container in pure Go.
+type nativeMuxer struct{}
+
+func (nativeMuxer) Mux(videoPath, audioPath, outputPath string) error {
+	return MuxNative(videoPath, audioPath, outputPath)
+}
+
+// mp4Track holds the parts of a demuxed m4s file needed to fold it into a
+// merged MP4: the moov-level mvhd (only kept for the video track, since a
+// file only needs one), a private copy of the trak box (patched in place
+// once we know where its samples land in the merged mdat), the raw sample
+// bytes, and the byte offset those samples used to live at in the source
+// file so we can compute how far they moved.
+type mp4Track struct {
+	mvhd         []byte
+	trak         []byte
+	mdatData     []byte
+	mdatFileOffs int64
+}
+
+// MuxNative reads the demuxed video/audio m4s elementary streams and
+// rewrites a single ftyp+moov+mdat MP4 holding both tracks, without
+// shelling out to MP4Box. The merged mdat holds all video sample bytes
+// followed by all audio sample bytes; samples of the two tracks are not
+// interleaved. It recomputes each track's chunk-offset table (stco/co64)
+// so samples keep pointing at the right bytes once both mdats are merged
+// into one.
+func MuxNative(videoPath, audioPath, outPath string) error {
+	video, err := readMP4Track(videoPath, true)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	audio, err := readMP4Track(audioPath, false)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	ftyp := buildFtyp()
+	moovLen := 8 + len(video.mvhd) + len(video.trak) + len(audio.trak)
+
+	mdatDataLen := int64(len(video.mdatData) + len(audio.mdatData))
+	mdatHeaderLen := int64(8)
+	if mdatDataLen+8 > math.MaxUint32 {
+		mdatHeaderLen = 16
+	}
+
+	newVideoDataOffset := int64(len(ftyp)) + int64(moovLen) + mdatHeaderLen
+	newAudioDataOffset := newVideoDataOffset + int64(len(video.mdatData))
+
+	if err := patchChunkOffsets(video.trak, newVideoDataOffset-video.mdatFileOffs); err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if err := patchChunkOffsets(audio.trak, newAudioDataOffset-audio.mdatFileOffs); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	defer out.Close()
+
+	if _, err := out.Write(ftyp); err != nil {
+		return err
+	}
+	if err := writeBoxHeader(out, "moov", int64(moovLen)); err != nil {
+		return err
+	}
+	for _, b := range [][]byte{video.mvhd, video.trak, audio.trak} {
+		if _, err := out.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := writeMdatHeader(out, mdatHeaderLen, mdatDataLen); err != nil {
+		return err
+	}
+	if _, err := out.Write(video.mdatData); err != nil {
+		return err
+	}
+	if _, err := out.Write(audio.mdatData); err != nil {
+		return err
+	}
+
+	logrus.Info("使用内置Go混流器合成MP4,无需外部MP4Box")
+	return nil
+}
+
+// readMP4Track loads an m4s file and pulls out the pieces MuxNative needs.
+// mvhd is only kept when wantMvhd is set (the merged file only needs one,
+// taken from the video track).
+func readMP4Track(path string, wantMvhd bool) (*mp4Track, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	moovOff, moovSize, ok := findChild(data, "moov")
+	if !ok {
+		return nil, fmt.Errorf("%s: no moov box found", path)
+	}
+	mdatOff, mdatSize, ok := findChild(data, "mdat")
+	if !ok {
+		return nil, fmt.Errorf("%s: no mdat box found", path)
+	}
+
+	moovBytes := data[moovOff : moovOff+moovSize]
+
+	trakOff, trakSize, err := findNestedBoxPath(moovBytes, "trak")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Copy the trak bytes out of moovBytes so patching its chunk-offset
+	// table doesn't alias the original file buffer.
+	trak := append([]byte(nil), moovBytes[trakOff:trakOff+trakSize]...)
+
+	var mvhd []byte
+	if wantMvhd {
+		mvhdOff, mvhdSize, err := findNestedBoxPath(moovBytes, "mvhd")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		mvhd = append([]byte(nil), moovBytes[mvhdOff:mvhdOff+mvhdSize]...)
+	}
+
+	_, mdatHeaderLen, _, err := readBoxHeader(data[mdatOff:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	mdatDataOffset := mdatOff + mdatHeaderLen
+	mdatData := append([]byte(nil), data[mdatDataOffset:mdatOff+mdatSize]...)
+
+	return &mp4Track{
+		mvhd:         mvhd,
+		trak:         trak,
+		mdatData:     mdatData,
+		mdatFileOffs: int64(mdatDataOffset),
+	}, nil
+}
+
+// patchChunkOffsets shifts every sample's recorded position in trak's
+// stco/co64 chunk-offset table by delta, so samples that used to live at
+// the start of their own file's mdat point at their new spot in the
+// merged mdat instead.
+func patchChunkOffsets(trak []byte, delta int64) error {
+	if off, size, err := findNestedBoxPath(trak, "mdia", "minf", "stbl", "stco"); err == nil {
+		return patchStco(trak[off:off+size], delta)
+	}
+	if off, size, err := findNestedBoxPath(trak, "mdia", "minf", "stbl", "co64"); err == nil {
+		return patchCo64(trak[off:off+size], delta)
+	}
+	return errors.New("no stco/co64 box found in trak")
+}
+
+// patchStco rewrites a 32-bit chunk-offset table (box: full bytes
+// including the 8-byte header, 4-byte version/flags, 4-byte entry count).
+func patchStco(box []byte, delta int64) error {
+	if len(box) < 16 {
+		return errors.New("stco box too short")
+	}
+	entryCount := binary.BigEndian.Uint32(box[12:16])
+	pos := 16
+	for i := uint32(0); i < entryCount; i++ {
+		if pos+4 > len(box) {
+			return errors.New("stco entry count exceeds box size")
+		}
+		newOffset := int64(binary.BigEndian.Uint32(box[pos:pos+4])) + delta
+		if newOffset < 0 || newOffset > math.MaxUint32 {
+			return fmt.Errorf("stco offset %d out of uint32 range after patch", newOffset)
+		}
+		binary.BigEndian.PutUint32(box[pos:pos+4], uint32(newOffset))
+		pos += 4
+	}
+	return nil
+}
+
+// patchCo64 is patchStco's 64-bit counterpart.
+func patchCo64(box []byte, delta int64) error {
+	if len(box) < 16 {
+		return errors.New("co64 box too short")
+	}
+	entryCount := binary.BigEndian.Uint32(box[12:16])
+	pos := 16
+	for i := uint32(0); i < entryCount; i++ {
+		if pos+8 > len(box) {
+			return errors.New("co64 entry count exceeds box size")
+		}
+		newOffset := int64(binary.BigEndian.Uint64(box[pos:pos+8])) + delta
+		if newOffset < 0 {
+			return fmt.Errorf("co64 offset %d negative after patch", newOffset)
+		}
+		binary.BigEndian.PutUint64(box[pos:pos+8], uint64(newOffset))
+		pos += 8
+	}
+	return nil
+}
+
+// readBoxHeader parses the size+type header at the start of b, handling
+// the 64-bit extended-size and size-extends-to-EOF (size==0) cases.
+func readBoxHeader(b []byte) (boxType string, headerLen, totalSize int, err error) {
+	if len(b) < 8 {
+		return "", 0, 0, errors.New("buffer too short for box header")
+	}
+	size := binary.BigEndian.Uint32(b[0:4])
+	boxType = string(b[4:8])
+	switch size {
+	case 0:
+		return boxType, 8, len(b), nil
+	case 1:
+		if len(b) < 16 {
+			return "", 0, 0, errors.New("buffer too short for 64-bit box header")
+		}
+		return boxType, 16, int(binary.BigEndian.Uint64(b[8:16])), nil
+	default:
+		return boxType, 8, int(size), nil
+	}
+}
+
+// findChild scans the immediate children of payload (a box's content, or a
+// sequence of sibling top-level boxes) for the first one of type want,
+// returning its offset and total size within payload.
+func findChild(payload []byte, want string) (offset, size int, ok bool) {
+	pos := 0
+	for pos+8 <= len(payload) {
+		boxType, _, totalSize, err := readBoxHeader(payload[pos:])
+		if err != nil || totalSize <= 0 || pos+totalSize > len(payload) {
+			return 0, 0, false
+		}
+		if boxType == want {
+			return pos, totalSize, true
+		}
+		pos += totalSize
+	}
+	return 0, 0, false
+}
+
+// findNestedBoxPath descends through boxBytes following path, e.g.
+// findNestedBoxPath(trak, "mdia", "minf", "stbl", "stco"), returning the
+// final box's offset and size relative to the start of boxBytes.
+func findNestedBoxPath(boxBytes []byte, path ...string) (offset, size int, err error) {
+	offset, size = 0, len(boxBytes)
+	for _, want := range path {
+		_, headerLen, totalSize, herr := readBoxHeader(boxBytes[offset : offset+size])
+		if herr != nil {
+			return 0, 0, herr
+		}
+		payloadStart := offset + headerLen
+		payloadEnd := offset + totalSize
+		childOff, childSize, ok := findChild(boxBytes[payloadStart:payloadEnd], want)
+		if !ok {
+			return 0, 0, fmt.Errorf("box %q not found", want)
+		}
+		offset = payloadStart + childOff
+		size = childSize
+	}
+	return offset, size, nil
+}
+
+// buildFtyp returns a minimal isom/mp42 ftyp box.
+func buildFtyp() []byte {
+	var buf bytes.Buffer
+	brands := []string{"isom", "mp42"}
+	size := 8 + 4 + 4 + 4*len(brands)
+	binary.Write(&buf, binary.BigEndian, uint32(size))
+	buf.WriteString("ftyp")
+	buf.WriteString("isom")                           // major brand
+	binary.Write(&buf, binary.BigEndian, uint32(512)) // minor version
+	for _, b := range brands {
+		buf.WriteString(b)
+	}
+	return buf.Bytes()
+}
+
+// writeBoxHeader writes a standard 32-bit size+type box header.
+func writeBoxHeader(w *os.File, boxType string, size int64) error {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(size))
+	buf.WriteString(boxType)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeMdatHeader writes the mdat header, using the 64-bit extended-size
+// form when the payload won't fit in a 32-bit size field.
+func writeMdatHeader(w *os.File, headerLen, dataLen int64) error {
+	var buf bytes.Buffer
+	if headerLen == 16 {
+		binary.Write(&buf, binary.BigEndian, uint32(1))
+		buf.WriteString("mdat")
+		binary.Write(&buf, binary.BigEndian, uint64(dataLen+16))
+	} else {
+		binary.Write(&buf, binary.BigEndian, uint32(dataLen+8))
+		buf.WriteString("mdat")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
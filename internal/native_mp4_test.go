@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// box builds a complete ISO-BMFF box (standard 32-bit size header) from a
+// 4-character type and payload bytes.
+func box(boxType string, payload []byte) []byte {
+	buf := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	copy(buf[4:8], boxType)
+	copy(buf[8:], payload)
+	return buf
+}
+
+// stcoBox builds a stco box with the given chunk offsets.
+func stcoBox(offsets ...uint32) []byte {
+	payload := make([]byte, 8+4*len(offsets))
+	binary.BigEndian.PutUint32(payload[4:8], uint32(len(offsets)))
+	for i, off := range offsets {
+		binary.BigEndian.PutUint32(payload[8+4*i:12+4*i], off)
+	}
+	return box("stco", payload)
+}
+
+func TestFindChildAndNestedBoxPath(t *testing.T) {
+	stco := stcoBox(100, 200, 300)
+	stbl := box("stbl", stco)
+	minf := box("minf", stbl)
+	mdia := box("mdia", minf)
+	trak := box("trak", mdia)
+
+	// findChild scans a box's payload, not the box itself.
+	off, size, ok := findChild(trak[8:], "mdia")
+	if !ok || off != 0 || size != len(mdia) {
+		t.Fatalf("findChild(trak payload, mdia) = (%d, %d, %v), want (0, %d, true)", off, size, ok, len(mdia))
+	}
+
+	off, size, err := findNestedBoxPath(trak, "mdia", "minf", "stbl", "stco")
+	if err != nil {
+		t.Fatalf("findNestedBoxPath: %v", err)
+	}
+	if size != len(stco) {
+		t.Fatalf("found stco size = %d, want %d", size, len(stco))
+	}
+	if got := trak[off : off+size]; string(got[4:8]) != "stco" {
+		t.Fatalf("found box type = %q, want stco", got[4:8])
+	}
+}
+
+func TestPatchChunkOffsets(t *testing.T) {
+	stco := stcoBox(100, 200, 300)
+	stbl := box("stbl", stco)
+	minf := box("minf", stbl)
+	mdia := box("mdia", minf)
+	trak := box("trak", mdia)
+
+	const delta = 50
+	if err := patchChunkOffsets(trak, delta); err != nil {
+		t.Fatalf("patchChunkOffsets: %v", err)
+	}
+
+	off, size, err := findNestedBoxPath(trak, "mdia", "minf", "stbl", "stco")
+	if err != nil {
+		t.Fatalf("findNestedBoxPath after patch: %v", err)
+	}
+	patched := trak[off : off+size]
+	entryCount := binary.BigEndian.Uint32(patched[12:16])
+	want := []uint32{150, 250, 350}
+	if int(entryCount) != len(want) {
+		t.Fatalf("entry count = %d, want %d", entryCount, len(want))
+	}
+	for i, w := range want {
+		got := binary.BigEndian.Uint32(patched[16+4*i : 20+4*i])
+		if got != w {
+			t.Errorf("entry %d = %d, want %d", i, got, w)
+		}
+	}
+}
+
+func TestPatchChunkOffsetsMissingStco(t *testing.T) {
+	mdia := box("mdia", box("minf", box("stbl", nil)))
+	trak := box("trak", mdia)
+	if err := patchChunkOffsets(trak, 1); err == nil {
+		t.Fatal("expected error when no stco/co64 box is present")
+	}
+}
+
+// buildM4S assembles a minimal single-track MP4 (moov+mdat, no ftyp) whose
+// stco table correctly points at the first byte of its own mdat payload,
+// the way a real demuxed m4s segment would.
+func buildM4S(t *testing.T, path string, mdatPayload []byte) {
+	t.Helper()
+
+	stco := stcoBox(0) // placeholder, patched below once the real layout is known
+	trak := box("trak", box("mdia", box("minf", box("stbl", stco))))
+	mvhd := box("mvhd", make([]byte, 100))
+	moov := box("moov", append(append([]byte{}, mvhd...), trak...))
+	mdat := box("mdat", mdatPayload)
+
+	data := append(append([]byte{}, moov...), mdat...)
+	ownMdatDataOffset := uint32(len(moov) + 8)
+
+	trakOff, _, err := findNestedBoxPath(moov, "trak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stcoOff, _, err := findNestedBoxPath(moov[trakOff:], "mdia", "minf", "stbl", "stco")
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(data[trakOff+stcoOff+16:trakOff+stcoOff+20], ownMdatDataOffset)
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMuxNativeRewritesChunkOffsets(t *testing.T) {
+	dir := t.TempDir()
+
+	videoMdat := []byte("VIDEO-SAMPLE-DATA")
+	audioMdat := []byte("AUDIO-SAMPLE-DATA")
+	videoPath := filepath.Join(dir, "video.m4s")
+	audioPath := filepath.Join(dir, "audio.m4s")
+	buildM4S(t, videoPath, videoMdat)
+	buildM4S(t, audioPath, audioMdat)
+
+	outPath := filepath.Join(dir, "out.mp4")
+	if err := MuxNative(videoPath, audioPath, outPath); err != nil {
+		t.Fatalf("MuxNative: %v", err)
+	}
+
+	out, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moovOff, moovSize, ok := findChild(out, "moov")
+	if !ok {
+		t.Fatal("output has no moov box")
+	}
+	mdatOff, _, ok := findChild(out, "mdat")
+	if !ok {
+		t.Fatal("output has no mdat box")
+	}
+	_, mdatHeaderLen, _, err := readBoxHeader(out[mdatOff:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdatDataOffset := mdatOff + mdatHeaderLen
+
+	moov := out[moovOff : moovOff+moovSize]
+	trakOff, _, err := findNestedBoxPath(moov, "trak")
+	if err != nil {
+		t.Fatalf("video trak not found in output moov: %v", err)
+	}
+	stcoOff, _, err := findNestedBoxPath(moov[trakOff:], "mdia", "minf", "stbl", "stco")
+	if err != nil {
+		t.Fatalf("video stco not found: %v", err)
+	}
+	videoSampleOffset := binary.BigEndian.Uint32(moov[trakOff+stcoOff+16 : trakOff+stcoOff+20])
+
+	if int(videoSampleOffset) != mdatDataOffset {
+		t.Errorf("patched video sample offset = %d, want %d (start of merged mdat)", videoSampleOffset, mdatDataOffset)
+	}
+	if got := string(out[videoSampleOffset : int(videoSampleOffset)+len(videoMdat)]); got != string(videoMdat) {
+		t.Errorf("sample at patched offset = %q, want %q", got, videoMdat)
+	}
+}